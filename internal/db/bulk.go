@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// BulkInsert serializes rows (a slice) into a single PostgREST POST,
+// inserting every row in one round trip instead of issuing N sequential
+// requests.
+func (s *SupabaseClient) BulkInsert(table string, rows any) ([]byte, error) {
+	return s.BulkInsertContext(context.Background(), table, rows)
+}
+
+// BulkInsertContext is BulkInsert with an explicit context. Unlike
+// GETContext/PATCHContext/DELETEContext, it deliberately isn't routed
+// through doWithRetry: an insert isn't idempotent (same reasoning as
+// POSTContext), so blindly resending it after a response is lost to a
+// network blip would risk inserting the rows twice.
+func (s *SupabaseClient) BulkInsertContext(ctx context.Context, table string, rows any) ([]byte, error) {
+	url := fmt.Sprintf("%s/rest/v1/%s?select=*", s.URL, table)
+
+	resp, err := s.Client.R().SetContext(ctx).SetBody(rows).Post(url)
+	if err != nil {
+		return nil, err
+	}
+
+	body := resp.Body()
+
+	if resp.StatusCode() != http.StatusCreated {
+		return nil, errorForStatus(resp.StatusCode(), body)
+	}
+
+	return body, nil
+}
+
+// BulkUpsert inserts rows, updating any row whose onConflict columns
+// already match an existing record instead of failing with a conflict.
+func (s *SupabaseClient) BulkUpsert(table string, rows any, onConflict string) ([]byte, error) {
+	return s.BulkUpsertContext(context.Background(), table, rows, onConflict)
+}
+
+// BulkUpsertContext is BulkUpsert with an explicit context.
+func (s *SupabaseClient) BulkUpsertContext(ctx context.Context, table string, rows any, onConflict string) ([]byte, error) {
+	query := fmt.Sprintf("select=*&on_conflict=%s", onConflict)
+	return s.bulkUpsertContext(ctx, table, query, rows)
+}
+
+// bulkUpsertContext does the actual PostgREST upsert POST. It takes a
+// pre-built query string so Query.Execute can carry through any
+// Select/filters the caller chained onto the builder instead of always
+// hardcoding select=*.
+//
+// This is routed through doWithRetry even though the request is a POST:
+// resending it is safe only because Prefer: resolution=merge-duplicates
+// makes a duplicate upsert of the same rows a no-op rather than a second
+// insert. Don't reuse this helper for a plain (non-merge) POST.
+func (s *SupabaseClient) bulkUpsertContext(ctx context.Context, table, query string, rows any) ([]byte, error) {
+	url := fmt.Sprintf("%s/rest/v1/%s?%s", s.URL, table, query)
+
+	resp, err := s.doWithRetry(ctx, "/rest/v1/"+table, func() (*resty.Response, error) {
+		return s.Client.R().
+			SetContext(ctx).
+			SetHeader("Prefer", "resolution=merge-duplicates,return=representation").
+			SetBody(rows).
+			Post(url)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body := resp.Body()
+
+	if resp.StatusCode() != http.StatusCreated && resp.StatusCode() != http.StatusOK {
+		return nil, errorForStatus(resp.StatusCode(), body)
+	}
+
+	return body, nil
+}
+
+// RPC invokes the Postgres function fn at /rest/v1/rpc/<fn>, so callers
+// can wrap multi-statement work in a single database transaction instead
+// of issuing several dependent REST calls.
+func (s *SupabaseClient) RPC(fn string, args any) ([]byte, error) {
+	return s.RPCContext(context.Background(), fn, args)
+}
+
+// RPCContext is RPC with an explicit context. It isn't routed through
+// doWithRetry: fn may wrap an arbitrary write (a payment or inventory
+// mutation), so there's no general way to know a resend after a lost
+// response wouldn't re-execute it.
+func (s *SupabaseClient) RPCContext(ctx context.Context, fn string, args any) ([]byte, error) {
+	url := fmt.Sprintf("%s/rest/v1/rpc/%s", s.URL, fn)
+
+	resp, err := s.Client.R().SetContext(ctx).SetBody(args).Post(url)
+	if err != nil {
+		return nil, err
+	}
+
+	body := resp.Body()
+
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		return nil, errorForStatus(resp.StatusCode(), body)
+	}
+
+	return body, nil
+}
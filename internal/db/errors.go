@@ -0,0 +1,52 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors returned by SupabaseClient methods so callers can use
+// errors.Is instead of string-matching the PostgREST/GoTrue response
+// body.
+var (
+	ErrNotFound     = errors.New("supabase: resource not found")
+	ErrConflict     = errors.New("supabase: conflict")
+	ErrUnauthorized = errors.New("supabase: unauthorized")
+	ErrRateLimited  = errors.New("supabase: rate limited")
+)
+
+// statusError pairs a sentinel error with the raw response so the
+// original PostgREST/GoTrue message isn't lost while still letting
+// callers match on the sentinel via errors.Is.
+type statusError struct {
+	sentinel error
+	status   int
+	body     []byte
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s (status %d): %s", e.sentinel, e.status, string(e.body))
+}
+
+func (e *statusError) Unwrap() error {
+	return e.sentinel
+}
+
+// errorForStatus maps an HTTP status code from Supabase to a typed
+// sentinel error, falling back to a generic formatted error for status
+// codes that don't have a dedicated sentinel.
+func errorForStatus(status int, body []byte) error {
+	switch status {
+	case http.StatusNotFound:
+		return &statusError{sentinel: ErrNotFound, status: status, body: body}
+	case http.StatusConflict:
+		return &statusError{sentinel: ErrConflict, status: status, body: body}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &statusError{sentinel: ErrUnauthorized, status: status, body: body}
+	case http.StatusTooManyRequests:
+		return &statusError{sentinel: ErrRateLimited, status: status, body: body}
+	default:
+		return fmt.Errorf("supabase error: status %d - %s", status, string(body))
+	}
+}
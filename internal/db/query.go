@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// OrderDirection specifies ascending or descending sort order for
+// Query.Order.
+type OrderDirection string
+
+const (
+	Asc  OrderDirection = "asc"
+	Desc OrderDirection = "desc"
+)
+
+// Query builds a PostgREST query string incrementally, URL-encoding
+// values so callers no longer hand-build queries with fmt.Sprintf.
+//
+//	db.From("listings").Select("id,title,price").Eq("user_id", uid).
+//		In("category", cats).Order("created_at", db.Desc).Range(0, 19).Build()
+type Query struct {
+	table      string
+	values     url.Values
+	rows       any
+	onConflict string
+}
+
+// From starts a new Query against table.
+func From(table string) *Query {
+	return &Query{
+		table:  table,
+		values: url.Values{},
+	}
+}
+
+// Upsert attaches rows (a slice) to be written by Execute as a bulk
+// upsert instead of a read.
+func (q *Query) Upsert(rows any) *Query {
+	q.rows = rows
+	return q
+}
+
+// OnConflict sets the comma-separated columns Execute upserts on; it
+// only takes effect once Upsert has attached rows.
+func (q *Query) OnConflict(columns string) *Query {
+	q.onConflict = columns
+	return q
+}
+
+// Select sets the columns (and embedded resources, e.g.
+// "*,seller:users(*)") to return.
+func (q *Query) Select(columns string) *Query {
+	q.values.Set("select", columns)
+	return q
+}
+
+// Eq filters column equal to value.
+func (q *Query) Eq(column string, value any) *Query {
+	q.values.Add(column, fmt.Sprintf("eq.%v", value))
+	return q
+}
+
+// In filters column to one of values.
+func (q *Query) In(column string, values []string) *Query {
+	q.values.Add(column, fmt.Sprintf("in.(%s)", strings.Join(values, ",")))
+	return q
+}
+
+// Like filters column against a case-sensitive LIKE pattern.
+func (q *Query) Like(column, pattern string) *Query {
+	q.values.Add(column, fmt.Sprintf("like.%s", pattern))
+	return q
+}
+
+// Ilike filters column against a case-insensitive LIKE pattern.
+func (q *Query) Ilike(column, pattern string) *Query {
+	q.values.Add(column, fmt.Sprintf("ilike.%s", pattern))
+	return q
+}
+
+// Gte filters column greater than or equal to value.
+func (q *Query) Gte(column string, value any) *Query {
+	q.values.Add(column, fmt.Sprintf("gte.%v", value))
+	return q
+}
+
+// Lte filters column less than or equal to value.
+func (q *Query) Lte(column string, value any) *Query {
+	q.values.Add(column, fmt.Sprintf("lte.%v", value))
+	return q
+}
+
+// Or adds a PostgREST "or" filter group, e.g.
+// q.Or("price.lt.100", "category.eq.tools").
+func (q *Query) Or(conditions ...string) *Query {
+	q.values.Add("or", fmt.Sprintf("(%s)", strings.Join(conditions, ",")))
+	return q
+}
+
+// Fts filters column using full-text search (to_tsquery semantics).
+func (q *Query) Fts(column, term string) *Query {
+	q.values.Add(column, fmt.Sprintf("fts.%s", term))
+	return q
+}
+
+// Plfts filters column using full-text search (plainto_tsquery
+// semantics), which is more forgiving of free-text user input than Fts.
+func (q *Query) Plfts(column, term string) *Query {
+	q.values.Add(column, fmt.Sprintf("plfts.%s", term))
+	return q
+}
+
+// Order sorts the result set by column in the given direction.
+func (q *Query) Order(column string, dir OrderDirection) *Query {
+	q.values.Set("order", fmt.Sprintf("%s.%s", column, dir))
+	return q
+}
+
+// Range limits the result set to rows [from, to] inclusive.
+func (q *Query) Range(from, to int) *Query {
+	q.values.Set("offset", strconv.Itoa(from))
+	q.values.Set("limit", strconv.Itoa(to-from+1))
+	return q
+}
+
+// Build returns the URL-encoded query string, ready to append to a
+// /rest/v1/<table>? request.
+func (q *Query) Build() string {
+	return q.values.Encode()
+}
+
+// Pagination describes the slice of a result set returned by PostgREST,
+// parsed from the Content-Range response header (e.g. "0-19/243").
+type Pagination struct {
+	From  int
+	To    int
+	Total int
+}
+
+// Execute runs q against the REST API. If rows were attached via
+// Upsert, it performs a bulk upsert and returns a zero Pagination;
+// otherwise it performs a read, parsing the Content-Range header
+// Supabase returns into a Pagination.
+func (s *SupabaseClient) Execute(ctx context.Context, q *Query) ([]byte, Pagination, error) {
+	if q.rows != nil {
+		query := q.values
+		if query.Get("select") == "" {
+			query.Set("select", "*")
+		}
+		query.Set("on_conflict", q.onConflict)
+
+		body, err := s.bulkUpsertContext(ctx, q.table, query.Encode(), q.rows)
+		return body, Pagination{}, err
+	}
+
+	url := fmt.Sprintf("%s/rest/v1/%s?%s", s.URL, q.table, q.Build())
+
+	resp, err := s.doWithRetry(ctx, "/rest/v1/"+q.table, func() (*resty.Response, error) {
+		return s.Client.R().SetContext(ctx).Get(url)
+	})
+	if err != nil {
+		return nil, Pagination{}, err
+	}
+
+	body := resp.Body()
+
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		return nil, Pagination{}, errorForStatus(resp.StatusCode(), body)
+	}
+
+	return body, parseContentRange(resp.Header().Get("Content-Range")), nil
+}
+
+// parseContentRange parses a "0-19/243" (or "*/0") Content-Range header
+// into a Pagination, returning the zero value if it can't be parsed.
+func parseContentRange(header string) Pagination {
+	if header == "" {
+		return Pagination{}
+	}
+
+	rangePart, totalPart, ok := strings.Cut(header, "/")
+	if !ok {
+		return Pagination{}
+	}
+
+	var p Pagination
+	if from, to, ok := strings.Cut(rangePart, "-"); ok {
+		p.From, _ = strconv.Atoi(from)
+		p.To, _ = strconv.Atoi(to)
+	}
+	p.Total, _ = strconv.Atoi(totalPart)
+
+	return p
+}
@@ -0,0 +1,41 @@
+package db
+
+import "testing"
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   Pagination
+	}{
+		{
+			name:   "typical page",
+			header: "0-19/243",
+			want:   Pagination{From: 0, To: 19, Total: 243},
+		},
+		{
+			name:   "empty result set",
+			header: "*/0",
+			want:   Pagination{From: 0, To: 0, Total: 0},
+		},
+		{
+			name:   "missing header",
+			header: "",
+			want:   Pagination{},
+		},
+		{
+			name:   "malformed header",
+			header: "not-a-range",
+			want:   Pagination{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseContentRange(tt.header)
+			if got != tt.want {
+				t.Errorf("parseContentRange(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ErrUpstreamUnavailable is returned when the circuit breaker for an
+// endpoint prefix is open, so the server fails fast instead of piling up
+// goroutines against a downed Supabase instance.
+var ErrUpstreamUnavailable = errors.New("supabase: upstream unavailable")
+
+// ClientOptions tunes the retry and circuit-breaker behavior of a
+// SupabaseClient. Use DefaultClientOptions for sane defaults.
+type ClientOptions struct {
+	// MaxRetries is how many additional attempts follow the first one.
+	MaxRetries int
+	// BaseDelay is the starting backoff before jitter is applied.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff, regardless of attempt count.
+	MaxDelay time.Duration
+	// BreakerThreshold is how many consecutive failures open the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before allowing
+	// a half-open trial request.
+	BreakerCooldown time.Duration
+}
+
+// DefaultClientOptions returns the retry/breaker tuning used when
+// NewSupabaseClient isn't given explicit options.
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		MaxRetries:       3,
+		BaseDelay:        100 * time.Millisecond,
+		MaxDelay:         2 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// breakerState is the state of a circuitBreaker.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker trips after a run of consecutive failures and fails
+// fast until its cooldown elapses, at which point it allows a single
+// half-open trial request.
+type circuitBreaker struct {
+	mu               sync.Mutex
+	state            breakerState
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight bool
+	threshold        int
+	cooldown         time.Duration
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a request may proceed. It flips an open breaker
+// to half-open once its cooldown has elapsed, but only lets a single
+// trial request through while half-open — concurrent callers otherwise
+// racing in right as the cooldown expires are turned away until that
+// trial reports success or failure.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerClosed:
+		return true
+	case breakerHalfOpen:
+		if b.halfOpenInFlight {
+			return false
+		}
+		b.halfOpenInFlight = true
+		return true
+	default: // breakerOpen
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.halfOpenInFlight = true
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker and resets its failure count.
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.halfOpenInFlight = false
+	b.state = breakerClosed
+}
+
+// RecordFailure counts a failure, opening the breaker once the
+// threshold is reached (or immediately if a half-open trial failed).
+func (b *circuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+	b.halfOpenInFlight = false
+}
+
+// metrics holds simple in-memory counters for retries, breaker trips,
+// and response status buckets. It's deliberately dependency-free;
+// scrape Snapshot into a real Prometheus registry at the call site.
+type metrics struct {
+	mu            sync.Mutex
+	retries       int64
+	breakerTrips  int64
+	statusBuckets map[string]int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{statusBuckets: make(map[string]int64)}
+}
+
+func (m *metrics) RecordRetry() {
+	m.mu.Lock()
+	m.retries++
+	m.mu.Unlock()
+}
+
+func (m *metrics) RecordBreakerTrip() {
+	m.mu.Lock()
+	m.breakerTrips++
+	m.mu.Unlock()
+}
+
+func (m *metrics) RecordStatus(status int) {
+	bucket := strconv.Itoa(status/100) + "xx"
+	m.mu.Lock()
+	m.statusBuckets[bucket]++
+	m.mu.Unlock()
+}
+
+// Snapshot returns a point-in-time copy of the counters.
+func (m *metrics) Snapshot() (retries, breakerTrips int64, statusBuckets map[string]int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buckets := make(map[string]int64, len(m.statusBuckets))
+	for k, v := range m.statusBuckets {
+		buckets[k] = v
+	}
+
+	return m.retries, m.breakerTrips, buckets
+}
+
+// Metrics returns a point-in-time snapshot of s's retry count, breaker
+// trip count, and response status-code buckets (e.g. "2xx", "5xx"), so
+// callers can scrape them into a real Prometheus registry.
+func (s *SupabaseClient) Metrics() (retries, breakerTrips int64, statusBuckets map[string]int64) {
+	return s.metrics.Snapshot()
+}
+
+// endpointPrefix buckets a Supabase request path into /rest/v1,
+// /auth/v1, or /storage/v1 so the circuit breaker trips per-subsystem
+// instead of globally.
+func endpointPrefix(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/rest/v1"):
+		return "/rest/v1"
+	case strings.HasPrefix(path, "/auth/v1"):
+		return "/auth/v1"
+	case strings.HasPrefix(path, "/storage/v1"):
+		return "/storage/v1"
+	default:
+		return path
+	}
+}
+
+// breakerFor returns (creating if necessary) the circuit breaker for
+// prefix.
+func (s *SupabaseClient) breakerFor(prefix string) *circuitBreaker {
+	s.breakersMu.Lock()
+	defer s.breakersMu.Unlock()
+
+	b, ok := s.breakers[prefix]
+	if !ok {
+		b = newCircuitBreaker(s.options.BreakerThreshold, s.options.BreakerCooldown)
+		s.breakers[prefix] = b
+	}
+
+	return b
+}
+
+// isRetryableStatus reports whether status is worth retrying: a
+// transient upstream hiccup rather than a client error.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryDelay computes the next backoff delay using exponential backoff
+// with full jitter, honoring a Retry-After header when present.
+func retryDelay(opts ClientOptions, attempt int, resp *resty.Response) time.Duration {
+	if resp != nil {
+		if ra := resp.Header().Get("Retry-After"); ra != "" {
+			if seconds, err := strconv.Atoi(ra); err == nil {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	backoff := opts.BaseDelay * time.Duration(uint64(1)<<uint(attempt))
+	if backoff > opts.MaxDelay || backoff <= 0 {
+		backoff = opts.MaxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// doWithRetry executes do, retrying on connection errors, 429s, and
+// 502/503/504 with exponential backoff and full jitter, while respecting
+// the circuit breaker for path's endpoint prefix.
+func (s *SupabaseClient) doWithRetry(ctx context.Context, path string, do func() (*resty.Response, error)) (*resty.Response, error) {
+	breaker := s.breakerFor(endpointPrefix(path))
+
+	if !breaker.Allow() {
+		s.metrics.RecordBreakerTrip()
+		return nil, ErrUpstreamUnavailable
+	}
+
+	var resp *resty.Response
+	var err error
+
+	for attempt := 0; attempt <= s.options.MaxRetries; attempt++ {
+		resp, err = do()
+
+		if err == nil {
+			s.metrics.RecordStatus(resp.StatusCode())
+
+			if !isRetryableStatus(resp.StatusCode()) {
+				breaker.RecordSuccess()
+				return resp, nil
+			}
+		}
+
+		if attempt == s.options.MaxRetries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			// The attempt that got us here failed; report it so a
+			// half-open trial doesn't leave the breaker stuck.
+			breaker.RecordFailure()
+			return resp, ctx.Err()
+		case <-time.After(retryDelay(s.options, attempt, resp)):
+		}
+
+		s.metrics.RecordRetry()
+	}
+
+	breaker.RecordFailure()
+	return resp, err
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := newCircuitBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if !b.Allow() {
+			t.Fatalf("attempt %d: expected breaker to allow requests below the threshold", i)
+		}
+		b.RecordFailure()
+	}
+
+	if !b.Allow() {
+		t.Fatal("expected breaker to still allow requests right at the threshold")
+	}
+	b.RecordFailure()
+
+	if b.Allow() {
+		t.Fatal("expected breaker to be open once the failure threshold is reached")
+	}
+}
+
+func TestCircuitBreakerHalfOpenAllowsASingleTrial(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure() // opens the breaker
+	if b.Allow() {
+		t.Fatal("expected breaker to reject requests while open")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatal("expected the first request after cooldown to be let through as a half-open trial")
+	}
+
+	// A concurrent request arriving while the trial is still in flight
+	// must be rejected, not let through.
+	if b.Allow() {
+		t.Fatal("expected a second concurrent request to be rejected while a half-open trial is in flight")
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure()
+	time.Sleep(20 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatal("expected the half-open trial to be allowed")
+	}
+	b.RecordFailure()
+
+	if b.Allow() {
+		t.Fatal("expected the breaker to reopen immediately when the half-open trial fails")
+	}
+}
+
+func TestCircuitBreakerHalfOpenSuccessCloses(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure()
+	time.Sleep(20 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatal("expected the half-open trial to be allowed")
+	}
+	b.RecordSuccess()
+
+	if !b.Allow() {
+		t.Fatal("expected the breaker to stay closed after the half-open trial succeeds")
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("expected state %v, got %v", breakerClosed, b.state)
+	}
+}
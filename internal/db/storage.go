@@ -0,0 +1,222 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Storage is a sub-client for the Supabase Storage API, scoped to the
+// parent SupabaseClient's URL and credentials.
+type Storage struct {
+	client *SupabaseClient
+}
+
+// Storage returns the storage sub-client for s.
+func (s *SupabaseClient) Storage() *Storage {
+	return &Storage{client: s}
+}
+
+// UploadOptions configures Storage.Upload.
+type UploadOptions struct {
+	// Upsert overwrites an existing object at the same path (PUT with
+	// x-upsert: true) instead of failing with a conflict.
+	Upsert bool
+	// CacheControl is sent as the object's Cache-Control header.
+	CacheControl string
+	// ContentType overrides content-type detection; if empty it's
+	// sniffed from the first 512 bytes of the body, falling back to the
+	// file extension in path.
+	ContentType string
+}
+
+// Upload streams r to bucket/path, detecting the content type via
+// http.DetectContentType when opts.ContentType isn't set.
+//
+// This deliberately isn't routed through doWithRetry: a plain upload is
+// a non-idempotent insert (same reasoning as BulkInsertContext), and
+// even with opts.Upsert the body is a streamed io.Reader that's already
+// been consumed by the time a retry would resend it.
+func (st *Storage) Upload(ctx context.Context, bucket, path string, r io.Reader, opts UploadOptions) error {
+	contentType := opts.ContentType
+	body := r
+
+	if contentType == "" {
+		sniff := make([]byte, 512)
+		n, err := io.ReadFull(r, sniff)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return fmt.Errorf("failed to read object for content-type detection: %w", err)
+		}
+		sniff = sniff[:n]
+		contentType = http.DetectContentType(sniff)
+		if contentType == "application/octet-stream" {
+			contentType = contentTypeFromExtension(path)
+		}
+		body = io.MultiReader(bytes.NewReader(sniff), r)
+	}
+
+	url := fmt.Sprintf("%s/storage/v1/object/%s/%s", st.client.URL, bucket, path)
+
+	req := st.client.Client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", contentType).
+		SetBody(body)
+
+	if opts.CacheControl != "" {
+		req.SetHeader("Cache-Control", opts.CacheControl)
+	}
+
+	method := req.Post
+	if opts.Upsert {
+		req.SetHeader("x-upsert", "true")
+		method = req.Put
+	}
+
+	resp, err := method(url)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return errorForStatus(resp.StatusCode(), resp.Body())
+	}
+
+	slog.Debug("uploaded object to supabase storage", "bucket", bucket, "path", path, "content_type", contentType)
+
+	return nil
+}
+
+// contentTypeFromExtension falls back to guessing a content type from
+// the file extension when http.DetectContentType can't tell image
+// formats like webp apart from generic binary data.
+func contentTypeFromExtension(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".png"):
+		return "image/png"
+	case strings.HasSuffix(path, ".webp"):
+		return "image/webp"
+	case strings.HasSuffix(path, ".jpg"), strings.HasSuffix(path, ".jpeg"):
+		return "image/jpeg"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// CreateSignedURL returns a time-limited URL for downloading the object
+// at bucket/path without requiring the caller to hold the service key.
+func (st *Storage) CreateSignedURL(ctx context.Context, bucket, path string, expires time.Duration) (string, error) {
+	url := fmt.Sprintf("%s/storage/v1/object/sign/%s/%s", st.client.URL, bucket, path)
+
+	payload := map[string]int{
+		"expiresIn": int(expires.Seconds()),
+	}
+
+	resp, err := st.client.doWithRetry(ctx, "/storage/v1", func() (*resty.Response, error) {
+		return st.client.Client.R().SetContext(ctx).SetBody(payload).Post(url)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return "", errorForStatus(resp.StatusCode(), resp.Body())
+	}
+
+	var signed struct {
+		SignedURL string `json:"signedURL"`
+	}
+	if err := json.Unmarshal(resp.Body(), &signed); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return st.client.URL + "/storage/v1" + signed.SignedURL, nil
+}
+
+// CreateSignedUploadURL returns a one-time URL a client can PUT an
+// object to directly, bypassing the Go server for large uploads.
+func (st *Storage) CreateSignedUploadURL(ctx context.Context, bucket, path string) (string, error) {
+	url := fmt.Sprintf("%s/storage/v1/object/upload/sign/%s/%s", st.client.URL, bucket, path)
+
+	resp, err := st.client.doWithRetry(ctx, "/storage/v1", func() (*resty.Response, error) {
+		return st.client.Client.R().SetContext(ctx).Post(url)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return "", errorForStatus(resp.StatusCode(), resp.Body())
+	}
+
+	var signed struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(resp.Body(), &signed); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return st.client.URL + "/storage/v1" + signed.URL, nil
+}
+
+// Delete removes one or more objects from bucket.
+func (st *Storage) Delete(ctx context.Context, bucket string, paths []string) error {
+	url := fmt.Sprintf("%s/storage/v1/object/%s", st.client.URL, bucket)
+
+	resp, err := st.client.doWithRetry(ctx, "/storage/v1", func() (*resty.Response, error) {
+		return st.client.Client.R().
+			SetContext(ctx).
+			SetBody(map[string][]string{"prefixes": paths}).
+			Delete(url)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return errorForStatus(resp.StatusCode(), resp.Body())
+	}
+
+	return nil
+}
+
+// Move relocates an object from fromPath to toPath within bucket.
+func (st *Storage) Move(ctx context.Context, bucket, fromPath, toPath string) error {
+	return st.moveOrCopy(ctx, "move", bucket, fromPath, toPath)
+}
+
+// Copy duplicates an object from fromPath to toPath within bucket.
+func (st *Storage) Copy(ctx context.Context, bucket, fromPath, toPath string) error {
+	return st.moveOrCopy(ctx, "copy", bucket, fromPath, toPath)
+}
+
+func (st *Storage) moveOrCopy(ctx context.Context, op, bucket, fromPath, toPath string) error {
+	url := fmt.Sprintf("%s/storage/v1/object/%s", st.client.URL, op)
+
+	payload := map[string]string{
+		"bucketId":       bucket,
+		"sourceKey":      fromPath,
+		"destinationKey": toPath,
+	}
+
+	resp, err := st.client.doWithRetry(ctx, "/storage/v1", func() (*resty.Response, error) {
+		return st.client.Client.R().SetContext(ctx).SetBody(payload).Post(url)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return errorForStatus(resp.StatusCode(), resp.Body())
+	}
+
+	return nil
+}
+
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"greenvue/lib"
@@ -25,6 +26,11 @@ type SupabaseClient struct {
 	URL    string
 	APIKey string
 	Client *resty.Client
+
+	options    ClientOptions
+	breakers   map[string]*circuitBreaker
+	breakersMu sync.Mutex
+	metrics    *metrics
 }
 
 // InitGlobalClient initializes the global Supabase client if it doesn't exist yet
@@ -61,8 +67,17 @@ func GetGlobalClient() *SupabaseClient {
 	return globalClient
 }
 
-// NewSupabaseClient creates a new Supabase client using environment variables
+// NewSupabaseClient creates a new Supabase client using environment
+// variables and the default retry/circuit-breaker tuning. Use
+// NewSupabaseClientWithOptions to customize that tuning.
 func NewSupabaseClient(useServiceKey ...bool) *SupabaseClient {
+	return NewSupabaseClientWithOptions(DefaultClientOptions(), useServiceKey...)
+}
+
+// NewSupabaseClientWithOptions creates a new Supabase client using
+// environment variables, applying opts to tune retries and the circuit
+// breaker.
+func NewSupabaseClientWithOptions(opts ClientOptions, useServiceKey ...bool) *SupabaseClient {
 	url := os.Getenv("SUPABASE_URL")
 
 	var apiKey string
@@ -90,17 +105,24 @@ func NewSupabaseClient(useServiceKey ...bool) *SupabaseClient {
 		SetHeader("Prefer", "return=representation")
 
 	return &SupabaseClient{
-		URL:    url,
-		APIKey: apiKey,
-		Client: client,
+		URL:      url,
+		APIKey:   apiKey,
+		Client:   client,
+		options:  opts,
+		breakers: make(map[string]*circuitBreaker),
+		metrics:  newMetrics(),
 	}
 }
 
-// GET performs a GET request to fetch data with optional query parameters
-func (s *SupabaseClient) GET(table, query string) ([]byte, error) {
+// GETContext performs a GET request to fetch data with optional query
+// parameters. ctx is forwarded to resty so a Fiber handler can cancel
+// the request once its client disconnects.
+func (s *SupabaseClient) GETContext(ctx context.Context, table, query string) ([]byte, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s?%s", s.URL, table, query)
 
-	resp, err := s.Client.R().Get(url)
+	resp, err := s.doWithRetry(ctx, "/rest/v1/"+table, func() (*resty.Response, error) {
+		return s.Client.R().SetContext(ctx).Get(url)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -108,22 +130,28 @@ func (s *SupabaseClient) GET(table, query string) ([]byte, error) {
 	body := resp.Body()
 
 	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
-		return nil, fmt.Errorf("supabase error: status %d - %s", resp.StatusCode(), string(body))
+		return nil, errorForStatus(resp.StatusCode(), body)
 	}
 
 	return body, nil
 }
 
-// POST creates a new record
-func (s *SupabaseClient) POST(table string, data any) ([]byte, error) {
+// GET is GETContext with context.Background(), kept for callers that
+// don't yet thread a context through.
+func (s *SupabaseClient) GET(table, query string) ([]byte, error) {
+	return s.GETContext(context.Background(), table, query)
+}
+
+// POSTContext creates a new record using ctx for cancellation.
+func (s *SupabaseClient) POSTContext(ctx context.Context, table string, data any) ([]byte, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s?select=*", s.URL, table)
 
 	resp, err := s.Client.R().
+		SetContext(ctx).
 		SetBody(data).
 		Post(url)
 
 	if err != nil {
-		fmt.Println("Error sending request:", err)
 		return nil, err
 	}
 
@@ -135,20 +163,26 @@ func (s *SupabaseClient) POST(table string, data any) ([]byte, error) {
 	}
 
 	if resp.StatusCode() != http.StatusCreated {
-		return nil, fmt.Errorf("supabase error: %s", string(body))
+		return nil, errorForStatus(resp.StatusCode(), body)
 	}
 
 	return body, nil
 }
 
-// PATCH updates an existing record by ID
-func (s *SupabaseClient) PATCH(table string, id uuid.UUID, data any) ([]byte, error) {
-	url := fmt.Sprintf("%s/rest/v1/%s?id=eq.%s", s.URL, table, id)
+// POST is POSTContext with context.Background(), kept for callers that
+// don't yet thread a context through.
+func (s *SupabaseClient) POST(table string, data any) ([]byte, error) {
+	return s.POSTContext(context.Background(), table, data)
+}
 
-	resp, err := s.Client.R().
-		SetBody(data).
-		Patch(url)
+// PATCHContext updates an existing record by ID using ctx for
+// cancellation.
+func (s *SupabaseClient) PATCHContext(ctx context.Context, table string, id uuid.UUID, data any) ([]byte, error) {
+	url := fmt.Sprintf("%s/rest/v1/%s?id=eq.%s", s.URL, table, id)
 
+	resp, err := s.doWithRetry(ctx, "/rest/v1/"+table, func() (*resty.Response, error) {
+		return s.Client.R().SetContext(ctx).SetBody(data).Patch(url)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -156,17 +190,26 @@ func (s *SupabaseClient) PATCH(table string, id uuid.UUID, data any) ([]byte, er
 	respBody := resp.Body()
 
 	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
-		return nil, fmt.Errorf("supabase PATCH error (%d): %s", resp.StatusCode(), string(respBody))
+		return nil, errorForStatus(resp.StatusCode(), respBody)
 	}
 
 	return respBody, nil
 }
 
-// DELETE removes a record based on condition
-func (s *SupabaseClient) DELETE(table, conditions string) ([]byte, error) {
+// PATCH is PATCHContext with context.Background(), kept for callers that
+// don't yet thread a context through.
+func (s *SupabaseClient) PATCH(table string, id uuid.UUID, data any) ([]byte, error) {
+	return s.PATCHContext(context.Background(), table, id, data)
+}
+
+// DELETEContext removes a record based on condition using ctx for
+// cancellation.
+func (s *SupabaseClient) DELETEContext(ctx context.Context, table, conditions string) ([]byte, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s?%s", s.URL, table, conditions)
 
-	resp, err := s.Client.R().Delete(url)
+	resp, err := s.doWithRetry(ctx, "/rest/v1/"+table, func() (*resty.Response, error) {
+		return s.Client.R().SetContext(ctx).Delete(url)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute DELETE request: %w", err)
 	}
@@ -174,16 +217,23 @@ func (s *SupabaseClient) DELETE(table, conditions string) ([]byte, error) {
 	respBody := resp.Body()
 
 	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
-		return nil, fmt.Errorf("DELETE operation failed (status %d): %s", resp.StatusCode(), string(respBody))
+		return nil, errorForStatus(resp.StatusCode(), respBody)
 	}
 
 	return respBody, nil
 }
 
-// UploadImage uploads an image to Supabase storage
-func (s *SupabaseClient) UploadImage(filename, bucket string, image []byte) ([]byte, error) {
+// DELETE is DELETEContext with context.Background(), kept for callers
+// that don't yet thread a context through.
+func (s *SupabaseClient) DELETE(table, conditions string) ([]byte, error) {
+	return s.DELETEContext(context.Background(), table, conditions)
+}
+
+// UploadImageContext uploads an image to Supabase storage. It accepts
+// ctx so a slow upload can be aborted when the request it's serving
+// goes away.
+func (s *SupabaseClient) UploadImageContext(ctx context.Context, filename, bucket string, image []byte) ([]byte, error) {
 	url := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.URL, bucket, filename)
-	fmt.Printf("Uploading to URL: %s\n", url)
 	contentType := "image/jpeg"
 	if strings.HasSuffix(filename, ".png") {
 		contentType = "image/png"
@@ -191,30 +241,38 @@ func (s *SupabaseClient) UploadImage(filename, bucket string, image []byte) ([]b
 		contentType = "image/webp"
 	}
 
-	fmt.Printf("Using content type: %s\n", contentType)
-
 	resp, err := s.Client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", contentType).
 		SetBody(image).
 		Post(url)
 
 	if err != nil {
-		fmt.Printf("Error sending request: %v\n", err)
 		return nil, err
 	}
 
 	body := resp.Body()
 
-	fmt.Printf("Status code: %d\n", resp.StatusCode())
 	if resp.StatusCode() >= 400 {
-		fmt.Printf("Error response: %s\n", string(body))
-		return nil, fmt.Errorf("supabase storage error (%d): %s", resp.StatusCode(), string(body))
+		return nil, errorForStatus(resp.StatusCode(), body)
 	}
 
 	return body, nil
 }
 
-// SignUp registers a new user
-func (s *SupabaseClient) SignUp(email, password string) (*lib.User, error) {
+// UploadImage is UploadImageContext with context.Background(), kept for
+// callers that don't yet thread a context through.
+//
+// Deprecated: prefer s.Storage().Upload, which streams the body,
+// detects content type from the data instead of the three hard-coded
+// extensions here, and can upsert existing objects.
+func (s *SupabaseClient) UploadImage(filename, bucket string, image []byte) ([]byte, error) {
+	return s.UploadImageContext(context.Background(), filename, bucket, image)
+}
+
+// SignUpContext registers a new user, propagating ctx to the underlying
+// request.
+func (s *SupabaseClient) SignUpContext(ctx context.Context, email, password string) (*lib.User, error) {
 	url := fmt.Sprintf("%s/auth/v1/signup", s.URL)
 
 	// Create request payload
@@ -224,6 +282,7 @@ func (s *SupabaseClient) SignUp(email, password string) (*lib.User, error) {
 	}
 
 	resp, err := s.Client.R().
+		SetContext(ctx).
 		SetBody(payload).
 		Post(url)
 
@@ -235,7 +294,7 @@ func (s *SupabaseClient) SignUp(email, password string) (*lib.User, error) {
 
 	// Check for HTTP errors
 	if resp.StatusCode() != http.StatusOK && resp.StatusCode() != http.StatusCreated {
-		return nil, fmt.Errorf("failed to sign up user: %s", string(body))
+		return nil, errorForStatus(resp.StatusCode(), body)
 	}
 
 	// Parse JSON response based on actual Supabase structure
@@ -262,8 +321,17 @@ func (s *SupabaseClient) SignUp(email, password string) (*lib.User, error) {
 	return user, nil
 }
 
-// Login authenticates a user
-func (s *SupabaseClient) Login(email, password string) (*lib.AuthResponse, error) {
+// SignUp is SignUpContext with context.Background(), kept for callers
+// that don't yet thread a context through.
+func (s *SupabaseClient) SignUp(email, password string) (*lib.User, error) {
+	return s.SignUpContext(context.Background(), email, password)
+}
+
+// LoginContext authenticates a user, propagating ctx to the underlying
+// request. Unlike SignUpContext, this is routed through doWithRetry:
+// logging in with the same credentials is idempotent, so resending it
+// after a lost response is safe.
+func (s *SupabaseClient) LoginContext(ctx context.Context, email, password string) (*lib.AuthResponse, error) {
 	url := fmt.Sprintf("%s/auth/v1/token?grant_type=password", s.URL)
 
 	// Create request payload
@@ -272,9 +340,12 @@ func (s *SupabaseClient) Login(email, password string) (*lib.AuthResponse, error
 		"password": password,
 	}
 
-	resp, err := s.Client.R().
-		SetBody(payload).
-		Post(url)
+	resp, err := s.doWithRetry(ctx, "/auth/v1/token", func() (*resty.Response, error) {
+		return s.Client.R().
+			SetContext(ctx).
+			SetBody(payload).
+			Post(url)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -294,20 +365,18 @@ func (s *SupabaseClient) Login(email, password string) (*lib.AuthResponse, error
 		}
 
 		switch errorResp.ErrorCode {
-		case "invalid_credentials":
-			return nil, fmt.Errorf("invalid_credentials")
-		case "email_not_confirmed":
-			return nil, fmt.Errorf("email_not_confirmed")
+		case "invalid_credentials", "email_not_confirmed":
+			return nil, &statusError{sentinel: ErrUnauthorized, status: resp.StatusCode(), body: body}
 		case "user_not_found":
-			return nil, fmt.Errorf("user_not_found")
+			return nil, &statusError{sentinel: ErrNotFound, status: resp.StatusCode(), body: body}
 		default:
-			return nil, fmt.Errorf("login_failed: %s", errorResp.Message)
+			return nil, errorForStatus(resp.StatusCode(), body)
 		}
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("login_failed: %s", string(body))
+		return nil, errorForStatus(resp.StatusCode(), body)
 	}
 
 	// Parse JSON response
@@ -319,10 +388,132 @@ func (s *SupabaseClient) Login(email, password string) (*lib.AuthResponse, error
 	return &authResp, nil
 }
 
-func (s *SupabaseClient) UpdateUser(id uuid.UUID, data map[string]any) (*lib.User, error) {
+// Login is LoginContext with context.Background(), kept for callers that
+// don't yet thread a context through.
+func (s *SupabaseClient) Login(email, password string) (*lib.AuthResponse, error) {
+	return s.LoginContext(context.Background(), email, password)
+}
+
+// RefreshTokenContext exchanges a refresh token for a new access/refresh
+// token pair. Unlike GET/PATCH/DELETE, this deliberately isn't routed
+// through doWithRetry: GoTrue rotates and invalidates the refresh token
+// on use, so blindly retrying a request whose response was merely lost
+// to a network blip would resend an already-consumed token and could
+// fail a refresh that actually succeeded.
+//
+// RefreshTokenContext, LogoutContext, and IntrospectContext give
+// SupabaseClient the ability to rotate, revoke, and verify a session, but
+// this package has no HTTP handler or middleware layer to call them from
+// — there isn't one anywhere in this tree yet. Until that layer exists,
+// a stolen access token still stays valid until it expires naturally;
+// wiring Logout into a request path (and Introspect into middleware that
+// rejects revoked tokens) is follow-up work, not done by this change.
+func (s *SupabaseClient) RefreshTokenContext(ctx context.Context, refreshToken string) (*lib.AuthResponse, error) {
+	url := fmt.Sprintf("%s/auth/v1/token?grant_type=refresh_token", s.URL)
+
+	payload := map[string]string{
+		"refresh_token": refreshToken,
+	}
+
+	resp, err := s.Client.R().
+		SetContext(ctx).
+		SetBody(payload).
+		Post(url)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	body := resp.Body()
+
+	if resp.StatusCode() != http.StatusOK {
+		return nil, errorForStatus(resp.StatusCode(), body)
+	}
+
+	var authResp lib.AuthResponse
+	if err := json.Unmarshal(body, &authResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &authResp, nil
+}
+
+// RefreshToken is RefreshTokenContext with context.Background(), kept
+// for callers that don't yet thread a context through. It lets callers
+// rotate a short-lived access token before it expires instead of
+// forcing the user to log in again.
+func (s *SupabaseClient) RefreshToken(refreshToken string) (*lib.AuthResponse, error) {
+	return s.RefreshTokenContext(context.Background(), refreshToken)
+}
+
+// LogoutContext revokes accessToken with Supabase so it can no longer be
+// used. ctx is threaded through to the underlying request.
+func (s *SupabaseClient) LogoutContext(ctx context.Context, accessToken string) error {
+	url := fmt.Sprintf("%s/auth/v1/logout", s.URL)
+
+	resp, err := s.doWithRetry(ctx, "/auth/v1/logout", func() (*resty.Response, error) {
+		return s.Client.R().SetContext(ctx).SetAuthToken(accessToken).Post(url)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK && resp.StatusCode() != http.StatusNoContent {
+		return errorForStatus(resp.StatusCode(), resp.Body())
+	}
+
+	return nil
+}
+
+// Logout is LogoutContext with context.Background(), kept for callers
+// that don't yet thread a context through. It ends the session on the
+// Supabase side; nothing in this package checks incoming access tokens
+// against it, so an access token already issued is not rejected here
+// before its natural expiry.
+func (s *SupabaseClient) Logout(accessToken string) error {
+	return s.LogoutContext(context.Background(), accessToken)
+}
+
+// IntrospectContext verifies accessToken against Supabase and returns
+// the claims describing the authenticated user, their role, and the
+// token's expiry.
+func (s *SupabaseClient) IntrospectContext(ctx context.Context, accessToken string) (*lib.TokenClaims, error) {
+	url := fmt.Sprintf("%s/auth/v1/user", s.URL)
+
+	resp, err := s.doWithRetry(ctx, "/auth/v1/user", func() (*resty.Response, error) {
+		return s.Client.R().SetContext(ctx).SetAuthToken(accessToken).Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	body := resp.Body()
+
+	if resp.StatusCode() != http.StatusOK {
+		return nil, errorForStatus(resp.StatusCode(), body)
+	}
+
+	var claims lib.TokenClaims
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &claims, nil
+}
+
+// Introspect is IntrospectContext with context.Background(), kept for
+// callers that don't yet thread a context through.
+func (s *SupabaseClient) Introspect(accessToken string) (*lib.TokenClaims, error) {
+	return s.IntrospectContext(context.Background(), accessToken)
+}
+
+// UpdateUserContext updates a user's auth record using ctx for
+// cancellation.
+func (s *SupabaseClient) UpdateUserContext(ctx context.Context, id uuid.UUID, data map[string]any) (*lib.User, error) {
 	url := fmt.Sprintf("%s/auth/v1/admin/users/%s", s.URL, id)
 
 	resp, err := s.Client.R().
+		SetContext(ctx).
 		SetBody(data).
 		Put(url)
 
@@ -333,7 +524,7 @@ func (s *SupabaseClient) UpdateUser(id uuid.UUID, data map[string]any) (*lib.Use
 	body := resp.Body()
 
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("update user failed: %s", string(body))
+		return nil, errorForStatus(resp.StatusCode(), body)
 	}
 
 	var user lib.User
@@ -343,3 +534,9 @@ func (s *SupabaseClient) UpdateUser(id uuid.UUID, data map[string]any) (*lib.Use
 
 	return &user, nil
 }
+
+// UpdateUser is UpdateUserContext with context.Background(), kept for
+// callers that don't yet thread a context through.
+func (s *SupabaseClient) UpdateUser(id uuid.UUID, data map[string]any) (*lib.User, error) {
+	return s.UpdateUserContext(context.Background(), id, data)
+}